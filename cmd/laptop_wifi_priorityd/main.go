@@ -0,0 +1,197 @@
+// Command laptop_wifi_priorityd is the long-running daemon that replaces the
+// old laptop_wifi_priority_service polling loop and the
+// laptop_wifi_priority_nm_pre_up one-shot dispatcher hook. It watches
+// NetworkManager over D-Bus and reacts to events instead of sleeping and
+// re-scanning on a fixed timer.
+package main
+
+import (
+	"flag"
+	"log"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/BPplays/laptop_wifi_priority/pkg/nmconfig"
+	"github.com/BPplays/laptop_wifi_priority/pkg/wflog"
+	"github.com/BPplays/laptop_wifi_priority/pkg/wifiprio"
+)
+
+const (
+	nmConfigPath   = "/etc/laptop_wifi_priority_nm_pre_up.yml"
+	wifiConfigPath = "/etc/laptop_wifi_priority.yml"
+
+	// debounceWindow coalesces bursts of D-Bus signals (e.g. every AP in a
+	// scan result firing its own PropertiesChanged) into one reconcile pass.
+	debounceWindow = 2 * time.Second
+
+	// scanFallbackInterval is the belt-and-braces RequestScan we issue even
+	// when no signals have fired, in case NM itself missed something.
+	scanFallbackInterval = 2 * time.Minute
+)
+
+const (
+	ifaceDevice   = "org.freedesktop.NetworkManager.Device"
+	ifaceWireless = "org.freedesktop.NetworkManager.Device.Wireless"
+	ifaceSettings = "org.freedesktop.NetworkManager.Settings"
+	ifaceProps    = "org.freedesktop.DBus.Properties"
+)
+
+func main() {
+	logLevel := flag.String("log-level", "", "override the configured log level (debug, info, warn, error)")
+	flag.Parse()
+
+	cfg, err := nmconfig.LoadConfig(nmConfigPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	logger, err := wflog.New(cfg.Log, *logLevel)
+	if err != nil {
+		log.Fatalf("bad log config: %v", err)
+	}
+
+	patcher, err := nmconfig.NewPatcher(cfg, logger)
+	if err != nil {
+		logger.Error("bad nmconfig", "error", err)
+		log.Fatalf("bad nmconfig: %v", err)
+	}
+
+	wifiCfg, err := wifiprio.LoadConfig(wifiConfigPath)
+	if err != nil {
+		logger.Info("no wifiprio config found, using defaults", "path", wifiConfigPath, "error", err)
+		wifiCfg = wifiprio.DefaultConfig()
+	}
+	manager := wifiprio.NewManager(wifiCfg, logger)
+	dwell := wifiprio.NewDwellScanner()
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		logger.Error("cannot connect to system bus", "error", err)
+		log.Fatalf("cannot connect to system bus: %v", err)
+	}
+
+	if err := subscribe(conn); err != nil {
+		logger.Error("failed to subscribe to NetworkManager signals", "error", err)
+		log.Fatalf("failed to subscribe to NetworkManager signals: %v", err)
+	}
+
+	// passMu serializes every Manager.Reconcile/Patcher.PatchAll pass.
+	// reconcile() below runs in whatever goroutine the debounce timer fires
+	// on, which can overlap with a previous still-running reconcile (Reset
+	// doesn't stop an already-executing AfterFunc) and with the independent
+	// resolveTicker pass -- neither Manager nor Patcher is safe for
+	// concurrent invocation (e.g. two PatchAll calls racing to create
+	// competing NM Checkpoints), so every pass takes this lock.
+	var passMu sync.Mutex
+
+	reconcile := func() {
+		passMu.Lock()
+		defer passMu.Unlock()
+		manager.Reconcile()
+		if err := patcher.PatchAll(); err != nil {
+			logger.Error("patch pass failed", "error", err)
+		}
+		if known, err := wifiprio.KnownSSIDs(); err == nil {
+			dwell.SetTargets(known)
+		}
+	}
+
+	// Run once immediately so we don't wait for the first event or the
+	// first fallback tick before doing anything useful.
+	reconcile()
+
+	signals := conn.Signal
+	ch := make(chan *dbus.Signal, 32)
+	signals(ch)
+
+	var debounce *time.Timer
+	fallback := time.NewTicker(scanFallbackInterval)
+	defer fallback.Stop()
+
+	// resolveTicker re-resolves hostname/CIDR DNS entries on their own
+	// cadence: PatchAll is otherwise only driven by reconcile(), which on a
+	// quiet network with no AP/connection churn may not run again for a long
+	// time, and resolve_interval_seconds needs to keep working regardless.
+	resolveTicker := time.NewTicker(patcher.ResolveInterval())
+	defer resolveTicker.Stop()
+
+	for {
+		select {
+		case sig, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !interesting(sig) {
+				continue
+			}
+			if debounce == nil {
+				debounce = time.AfterFunc(debounceWindow, reconcile)
+			} else {
+				debounce.Reset(debounceWindow)
+			}
+
+		case <-fallback.C:
+			dwellScanOnce(logger, dwell)
+
+		case <-resolveTicker.C:
+			passMu.Lock()
+			err := patcher.PatchAll()
+			passMu.Unlock()
+			if err != nil {
+				logger.Error("resolve-interval patch pass failed", "error", err)
+			}
+		}
+	}
+}
+
+// subscribe registers the match rules for the signals we care about:
+// PropertiesChanged on wireless devices, AccessPointAdded/Removed,
+// StateChanged, and Settings.NewConnection.
+func subscribe(conn *dbus.Conn) error {
+	rules := []string{
+		"type='signal',interface='" + ifaceProps + "',member='PropertiesChanged',arg0='" + ifaceWireless + "'",
+		"type='signal',interface='" + ifaceWireless + "',member='AccessPointAdded'",
+		"type='signal',interface='" + ifaceWireless + "',member='AccessPointRemoved'",
+		"type='signal',interface='" + ifaceDevice + "',member='StateChanged'",
+		"type='signal',interface='" + ifaceSettings + "',member='NewConnection'",
+	}
+	for _, rule := range rules {
+		call := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule)
+		if call.Err != nil {
+			return call.Err
+		}
+	}
+	return nil
+}
+
+func interesting(sig *dbus.Signal) bool {
+	switch sig.Name {
+	case ifaceProps + ".PropertiesChanged",
+		ifaceWireless + ".AccessPointAdded",
+		ifaceWireless + ".AccessPointRemoved",
+		ifaceDevice + ".StateChanged",
+		ifaceSettings + ".NewConnection":
+		return true
+	}
+	return false
+}
+
+// dwellScanOnce issues one targeted RequestScan for whichever known SSID is
+// next in the dwell rotation (skipping ones still backed off), and reports
+// the outcome back so repeatedly-empty SSIDs get scanned less often.
+func dwellScanOnce(logger *slog.Logger, dwell *wifiprio.DwellScanner) {
+	ssid, ok := dwell.Next()
+	if !ok {
+		return
+	}
+	elapsed, found, err := wifiprio.RequestScanForSSID(ssid)
+	if err != nil {
+		logger.Warn("dwell scan failed", "ssid", ssid, "action", "dwell_scan", "error", err)
+		return
+	}
+	logger.Debug("dwell scan done", "ssid", ssid, "action", "dwell_scan", "found", found)
+	dwell.Record(ssid, found, elapsed)
+}
@@ -0,0 +1,140 @@
+package nmconfig
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// CheckpointConfig controls the NM Checkpoint/Rollback safety net wrapped
+// around each PatchAll mutation pass, and the liveness probe that decides
+// whether a checkpoint gets kept or rolled back.
+type CheckpointConfig struct {
+	// RollbackTimeoutSeconds bounds how long NM will wait before rolling
+	// back on its own if we crash mid-patch. Defaults to 60.
+	RollbackTimeoutSeconds uint32 `yaml:"rollback_timeout_seconds"`
+
+	// ProbeDNSName, if set, is resolved as part of the liveness probe.
+	ProbeDNSName string `yaml:"probe_dns_name"`
+	// ProbeTCPAddr, if set, is TCP-dialed ("host:port") as part of the
+	// liveness probe.
+	ProbeTCPAddr string `yaml:"probe_tcp_addr"`
+	// ProbeTimeoutSeconds bounds each probe step. Defaults to 5.
+	ProbeTimeoutSeconds int `yaml:"probe_timeout_seconds"`
+}
+
+func (c *CheckpointConfig) rollbackTimeout() uint32 {
+	if c == nil || c.RollbackTimeoutSeconds == 0 {
+		return 60
+	}
+	return c.RollbackTimeoutSeconds
+}
+
+func (c *CheckpointConfig) probeTimeout() time.Duration {
+	if c == nil || c.ProbeTimeoutSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.ProbeTimeoutSeconds) * time.Second
+}
+
+// probe runs the configured liveness checks. With no probe target
+// configured at all, it passes trivially -- there's nothing to verify, so
+// the checkpoint is just a crash safety net rather than a rollback trigger.
+func (c *CheckpointConfig) probe() (bool, string) {
+	if c == nil || (c.ProbeDNSName == "" && c.ProbeTCPAddr == "") {
+		return true, "no probe configured"
+	}
+	timeout := c.probeTimeout()
+
+	if c.ProbeDNSName != "" {
+		r := net.Resolver{}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if _, err := r.LookupHost(ctx, c.ProbeDNSName); err != nil {
+			return false, fmt.Sprintf("resolving %s: %v", c.ProbeDNSName, err)
+		}
+	}
+
+	if c.ProbeTCPAddr != "" {
+		conn, err := net.DialTimeout("tcp", c.ProbeTCPAddr, timeout)
+		if err != nil {
+			return false, fmt.Sprintf("dialing %s: %v", c.ProbeTCPAddr, err)
+		}
+		conn.Close()
+	}
+
+	return true, "probe ok"
+}
+
+const nmBusName = "org.freedesktop.NetworkManager"
+
+// checkpointCreate wraps NetworkManager.CheckpointCreate over devices with
+// the given rollback timeout. Empty devices means "all devices".
+func checkpointCreate(conn *dbus.Conn, devices []dbus.ObjectPath, rollbackTimeout uint32) (dbus.ObjectPath, error) {
+	nm := conn.Object(nmBusName, dbus.ObjectPath("/org/freedesktop/NetworkManager"))
+	var cp dbus.ObjectPath
+	call := nm.Call(nmBusName+".CheckpointCreate", 0, devices, rollbackTimeout, uint32(0))
+	if call.Err != nil {
+		return "", call.Err
+	}
+	if err := call.Store(&cp); err != nil {
+		return "", err
+	}
+	return cp, nil
+}
+
+func checkpointDestroy(conn *dbus.Conn, cp dbus.ObjectPath) error {
+	nm := conn.Object(nmBusName, dbus.ObjectPath("/org/freedesktop/NetworkManager"))
+	return nm.Call(nmBusName+".CheckpointDestroy", 0, cp).Err
+}
+
+func checkpointRollback(conn *dbus.Conn, cp dbus.ObjectPath) (map[dbus.ObjectPath]uint32, error) {
+	nm := conn.Object(nmBusName, dbus.ObjectPath("/org/freedesktop/NetworkManager"))
+	var result map[dbus.ObjectPath]uint32
+	call := nm.Call(nmBusName+".CheckpointRollback", 0, cp)
+	if call.Err != nil {
+		return nil, call.Err
+	}
+	if err := call.Store(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// activeDevicesForConnections returns the object paths of devices currently
+// running any of the given connections, so the checkpoint only scopes the
+// devices we're actually about to touch rather than the whole machine.
+func activeDevicesForConnections(conn *dbus.Conn, paths map[dbus.ObjectPath]bool) ([]dbus.ObjectPath, error) {
+	nm := conn.Object(nmBusName, dbus.ObjectPath("/org/freedesktop/NetworkManager"))
+	activeConns, err := nm.GetProperty(nmBusName + ".ActiveConnections")
+	if err != nil {
+		return nil, err
+	}
+	acPaths, ok := activeConns.Value().([]dbus.ObjectPath)
+	if !ok {
+		return nil, fmt.Errorf("unexpected ActiveConnections type")
+	}
+
+	var devices []dbus.ObjectPath
+	for _, acPath := range acPaths {
+		ac := conn.Object(nmBusName, acPath)
+		connPathV, err := ac.GetProperty("org.freedesktop.NetworkManager.Connection.Active.Connection")
+		if err != nil {
+			continue
+		}
+		connPath, _ := connPathV.Value().(dbus.ObjectPath)
+		if !paths[connPath] {
+			continue
+		}
+		devsV, err := ac.GetProperty("org.freedesktop.NetworkManager.Connection.Active.Devices")
+		if err != nil {
+			continue
+		}
+		devs, _ := devsV.Value().([]dbus.ObjectPath)
+		devices = append(devices, devs...)
+	}
+	return devices, nil
+}
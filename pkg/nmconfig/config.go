@@ -0,0 +1,92 @@
+// Package nmconfig patches NetworkManager connection profiles (DNS servers,
+// the IPv6 token) based on which SSID/wired profile is being activated.
+//
+// It started life as the laptop_wifi_priority_nm_pre_up one-shot tool and is
+// now a library so the daemon in cmd/laptop_wifi_priorityd can drive it
+// reactively instead of re-execing it from an NM dispatcher hook.
+package nmconfig
+
+import (
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/BPplays/laptop_wifi_priority/pkg/wflog"
+)
+
+// DefaultResolveInterval is how often hostname entries in the DNS lists are
+// re-resolved when ResolveIntervalSeconds isn't set.
+const DefaultResolveInterval = 5 * time.Minute
+
+// Config holds the list of SSID prefixes and DNS/token settings.
+type Config struct {
+	Prefixes  []string   `yaml:"prefixes"`
+	PrivIPv6  []DNSEntry `yaml:"priv_ipv6"`
+	PrivIPv4  []DNSEntry `yaml:"priv_ipv4"`
+	PubIPv6   []DNSEntry `yaml:"pub_ipv6"`
+	PubIPv4   []DNSEntry `yaml:"pub_ipv4"`
+	Ipv6Token string     `yaml:"ipv6_token"`
+
+	// Posture gates whether a Prefixes match actually gets the private DNS
+	// profile. See PostureConfig.
+	Posture *PostureConfig `yaml:"posture"`
+
+	// ResolveIntervalSeconds overrides DefaultResolveInterval.
+	ResolveIntervalSeconds int `yaml:"resolve_interval_seconds"`
+
+	// Resolver controls how hostname entries above are resolved.
+	Resolver *ResolverConfig `yaml:"resolver"`
+
+	// Checkpoint controls the Checkpoint/Rollback safety net wrapped around
+	// each patch pass and its post-update liveness probe.
+	Checkpoint *CheckpointConfig `yaml:"checkpoint"`
+
+	// Metadata enables discovery of a "server" address substitutable into
+	// the DNS lists above via the ${metadata.server} token.
+	Metadata *MetadataConfig `yaml:"metadata"`
+
+	// Log controls the structured logger the daemon builds at startup. See
+	// package wflog.
+	Log *wflog.Config `yaml:"log"`
+}
+
+// ResolverConfig selects how DNS hostnames in the config are resolved.
+type ResolverConfig struct {
+	// Bootstrap, if set, is a "host:port" upstream queried directly instead
+	// of going through the system resolver. Use this to avoid the
+	// chicken-and-egg problem of needing working DNS to push the DNS
+	// settings in the first place.
+	Bootstrap string `yaml:"bootstrap"`
+}
+
+// ResolveInterval returns the configured resolve interval, or
+// DefaultResolveInterval if unset.
+func (c *Config) ResolveInterval() time.Duration {
+	if c.ResolveIntervalSeconds <= 0 {
+		return DefaultResolveInterval
+	}
+	return time.Duration(c.ResolveIntervalSeconds) * time.Second
+}
+
+// LoadConfig reads and parses the YAML config at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func hasPrefixAny(name string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if len(name) >= len(p) && name[:len(p)] == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package nmconfig
+
+// DNSEntry is one item in a priv_ipv4/priv_ipv6/pub_ipv4/pub_ipv6 list. Value
+// can be a literal IP, a CIDR (the address before the slash is used as
+// written, the mask is ignored),
+// or a hostname to be resolved periodically.
+//
+// It accepts either a bare scalar:
+//
+//	priv_ipv4:
+//	  - 10.0.0.1
+//	  - resolver.corp.example
+//
+// or a map when KeepRoute needs setting:
+//
+//	priv_ipv4:
+//	  - value: resolver.corp.example
+//	    keep_route: true
+type DNSEntry struct {
+	Value string `yaml:"value"`
+
+	// KeepRoute, when true, accumulates resolved addresses across refreshes
+	// instead of replacing them -- useful for a hostname whose answer set
+	// grows (e.g. round-robin resolvers behind a CDN) on a long-lived
+	// connection where dropping an old address could cut an in-use route.
+	KeepRoute bool `yaml:"keep_route"`
+}
+
+// UnmarshalYAML implements the scalar-or-map shorthand described above.
+func (e *DNSEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var plain string
+	if err := unmarshal(&plain); err == nil {
+		e.Value = plain
+		return nil
+	}
+
+	type entryAlias DNSEntry
+	var a entryAlias
+	if err := unmarshal(&a); err != nil {
+		return err
+	}
+	*e = DNSEntry(a)
+	return nil
+}
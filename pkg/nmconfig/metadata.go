@@ -0,0 +1,199 @@
+package nmconfig
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	gonm "github.com/Wifx/gonetworkmanager"
+)
+
+// metadataToken is substituted in priv_ipv4/priv_ipv6/pub_ipv4/pub_ipv6
+// entries with whatever discoverServer finds, so the same config works both
+// on a laptop roaming between physical networks (where the "server" is
+// discovered per-network) and on a cloud VM where the router is the
+// resolver.
+const metadataToken = "${metadata.server}"
+
+// MetadataConfig enables discovery of a "server" address, borrowing the
+// multi-strategy fallback pattern cloud-init providers use to find their
+// metadata service.
+type MetadataConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// DNSName is looked up first. Defaults to "data-server".
+	DNSName string `yaml:"dns_name"`
+}
+
+func (m *MetadataConfig) dnsName() string {
+	if m == nil || m.DNSName == "" {
+		return "data-server"
+	}
+	return m.DNSName
+}
+
+// discoverServer tries, in order: DNS lookup of cfg.DNSName, the DHCP server
+// identifier option on the active connection's DHCP4Config/DHCP6Config, and
+// the default gateway from /proc/net/route (falling back to
+// /proc/net/ipv6_route). It returns the first address any strategy finds.
+func discoverServer(cfg *MetadataConfig) (string, error) {
+	if cfg == nil || !cfg.Enabled {
+		return "", nil
+	}
+
+	if ips, err := net.LookupHost(cfg.dnsName()); err == nil && len(ips) > 0 {
+		return ips[0], nil
+	}
+
+	if addr, err := dhcpServerIdentifier(); err == nil && addr != "" {
+		return addr, nil
+	}
+
+	if addr, err := defaultGatewayV4(); err == nil && addr != "" {
+		return addr, nil
+	}
+	if addr, err := defaultGatewayV6(); err == nil && addr != "" {
+		return addr, nil
+	}
+
+	return "", fmt.Errorf("metadata: no discovery strategy found a server address")
+}
+
+// dhcpServerIdentifier reads the DHCP server identifier option off whichever
+// connection is currently active, checking DHCP4Config first and then
+// DHCP6Config so IPv6-only and dual-stack-preferring connections aren't
+// skipped straight to the gateway-parsing fallback.
+func dhcpServerIdentifier() (string, error) {
+	nm, err := gonm.NewNetworkManager()
+	if err != nil {
+		return "", err
+	}
+	active, err := nm.GetPropertyActiveConnections()
+	if err != nil {
+		return "", err
+	}
+	for _, ac := range active {
+		if dhcp4, err := ac.GetPropertyDHCP4Config(); err == nil && dhcp4 != nil {
+			if opts, err := dhcp4.GetPropertyOptions(); err == nil {
+				if v, ok := opts["dhcp_server_identifier"]; ok {
+					if s, ok := v.(string); ok && s != "" {
+						return s, nil
+					}
+				}
+			}
+		}
+		// DHCPv6 identifies the server by DUID, not a routable address, so
+		// there's no exact equivalent of dhcp_server_identifier here -- but
+		// some dhclient builds also surface "dhcp6_server_id" as a literal
+		// address, so take it when it parses as one.
+		if dhcp6, err := ac.GetPropertyDHCP6Config(); err == nil && dhcp6 != nil {
+			if opts, err := dhcp6.GetPropertyOptions(); err == nil {
+				if v, ok := opts["dhcp6_server_id"]; ok {
+					if s, ok := v.(string); ok && net.ParseIP(s) != nil {
+						return s, nil
+					}
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("no active connection had a usable DHCP server identifier")
+}
+
+// defaultGatewayV4 parses /proc/net/route for the default (0.0.0.0/0) route
+// and returns its gateway.
+func defaultGatewayV4() (string, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return parseDefaultGatewayV4(f)
+}
+
+// parseDefaultGatewayV4 does the actual /proc/net/route parsing, split out
+// from defaultGatewayV4 so the little-endian offset-counting below can be
+// unit tested without a real /proc/net/route.
+func parseDefaultGatewayV4(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		dest, gw := fields[1], fields[2]
+		if dest != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(gw)
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		// /proc/net/route stores the address little-endian.
+		ip := net.IPv4(raw[3], raw[2], raw[1], raw[0])
+		if ip.IsUnspecified() {
+			continue
+		}
+		return ip.String(), nil
+	}
+	return "", fmt.Errorf("no default route in /proc/net/route")
+}
+
+// defaultGatewayV6 parses /proc/net/ipv6_route for the default (::/0) route.
+func defaultGatewayV6() (string, error) {
+	f, err := os.Open("/proc/net/ipv6_route")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return parseDefaultGatewayV6(f)
+}
+
+// parseDefaultGatewayV6 does the actual /proc/net/ipv6_route parsing, split
+// out from defaultGatewayV6 so the field-offset parsing below can be unit
+// tested without a real /proc/net/ipv6_route.
+func parseDefaultGatewayV6(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		destHex, prefixLenHex, nextHopHex := fields[0], fields[1], fields[4]
+		if destHex != strings.Repeat("0", 32) {
+			continue
+		}
+		if n, err := strconv.ParseInt(prefixLenHex, 16, 32); err != nil || n != 0 {
+			continue
+		}
+		raw, err := hex.DecodeString(nextHopHex)
+		if err != nil || len(raw) != 16 {
+			continue
+		}
+		ip := net.IP(raw)
+		if ip.IsUnspecified() {
+			continue
+		}
+		return ip.String(), nil
+	}
+	return "", fmt.Errorf("no default route in /proc/net/ipv6_route")
+}
+
+// substituteMetadata replaces metadataToken in each entry's Value with
+// server. Entries without the token are returned unchanged.
+func substituteMetadata(entries []DNSEntry, server string) []DNSEntry {
+	if server == "" {
+		return entries
+	}
+	out := make([]DNSEntry, len(entries))
+	for i, e := range entries {
+		e.Value = strings.ReplaceAll(e.Value, metadataToken, server)
+		out[i] = e
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package nmconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDefaultGatewayV4(t *testing.T) {
+	const routes = "Iface\tDestination\tGateway\tFlags\tRefCnt\tUse\tMetric\tMask\tMTU\tWindow\tIRTT\n" +
+		"wlan0\t0000A8C0\t00000000\t0001\t0\t0\t600\t00FFFFFF\t0\t0\t0\n" +
+		"wlan0\t00000000\t0102A8C0\t0003\t0\t0\t600\t00000000\t0\t0\t0\n"
+
+	got, err := parseDefaultGatewayV4(strings.NewReader(routes))
+	if err != nil {
+		t.Fatalf("parseDefaultGatewayV4: %v", err)
+	}
+	if want := "192.168.2.1"; got != want {
+		t.Errorf("gateway = %q, want %q", got, want)
+	}
+}
+
+func TestParseDefaultGatewayV4NoDefaultRoute(t *testing.T) {
+	const routes = "Iface\tDestination\tGateway\tFlags\tRefCnt\tUse\tMetric\tMask\tMTU\tWindow\tIRTT\n" +
+		"wlan0\t0000A8C0\t00000000\t0001\t0\t0\t600\t00FFFFFF\t0\t0\t0\n"
+
+	if _, err := parseDefaultGatewayV4(strings.NewReader(routes)); err == nil {
+		t.Fatal("expected an error when there's no default route")
+	}
+}
+
+const (
+	// v6NonDefaultRoute is an on-link fe80::/64 route: not the default.
+	v6NonDefaultRoute = "fe800000000000000000000000000000 40 00000000000000000000000000000000 00 " +
+		"00000000000000000000000000000000 00000400 00000001 00000ba1 00200001 wlan0"
+	// v6DefaultRoute is a ::/0 default route via ::1.
+	v6DefaultRoute = "00000000000000000000000000000000 00 00000000000000000000000000000000 00 " +
+		"00000000000000000000000000000001 00000400 00000001 00000ba1 00200001 wlan0"
+)
+
+func TestParseDefaultGatewayV6(t *testing.T) {
+	routes := v6NonDefaultRoute + "\n" + v6DefaultRoute + "\n"
+
+	got, err := parseDefaultGatewayV6(strings.NewReader(routes))
+	if err != nil {
+		t.Fatalf("parseDefaultGatewayV6: %v", err)
+	}
+	if want := "::1"; got != want {
+		t.Errorf("gateway = %q, want %q", got, want)
+	}
+}
+
+func TestParseDefaultGatewayV6NoDefaultRoute(t *testing.T) {
+	routes := v6NonDefaultRoute + "\n"
+
+	if _, err := parseDefaultGatewayV6(strings.NewReader(routes)); err == nil {
+		t.Fatal("expected an error when there's no default route")
+	}
+}
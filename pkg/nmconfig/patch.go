@@ -0,0 +1,337 @@
+package nmconfig
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/godbus/dbus/v5"
+)
+
+// Patcher applies Config's DNS/token rules to the NM connections currently
+// known to Settings. It's cheap to construct and safe to reuse across
+// multiple PatchAll calls, which is what lets the daemon run it on every
+// relevant D-Bus signal instead of once per process lifetime.
+type Patcher struct {
+	cfg      *Config
+	posture  *PostureEvaluator
+	resolver Resolver
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu           sync.Mutex
+	lastResolved time.Time
+	// entries are the last entry lists actually resolved, i.e. cfg's lists
+	// after ${metadata.server} substitution -- what the resolvedList maps
+	// below are keyed by, which is not necessarily what's in cfg verbatim.
+	privIPv4Entries []DNSEntry
+	privIPv6Entries []DNSEntry
+	pubIPv4Entries  []DNSEntry
+	pubIPv6Entries  []DNSEntry
+	privIPv4        *resolvedList
+	privIPv6        *resolvedList
+	pubIPv4         *resolvedList
+	pubIPv6         *resolvedList
+
+	sigMu   sync.Mutex
+	lastSig map[dbus.ObjectPath]string
+}
+
+// NewPatcher builds a Patcher bound to cfg. A nil logger falls back to
+// slog.Default() so callers that don't care about logging (e.g. one-off
+// tooling) don't have to build one.
+func NewPatcher(cfg *Config, logger *slog.Logger) (*Patcher, error) {
+	posture, err := BuildPostureEvaluator(cfg.Posture)
+	if err != nil {
+		return nil, fmt.Errorf("posture config: %w", err)
+	}
+
+	var resolver Resolver = NewSystemResolver()
+	if cfg.Resolver != nil && cfg.Resolver.Bootstrap != "" {
+		resolver = &BootstrapResolver{Upstream: cfg.Resolver.Bootstrap}
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Patcher{
+		cfg:      cfg,
+		posture:  posture,
+		resolver: resolver,
+		interval: cfg.ResolveInterval(),
+		logger:   logger,
+		lastSig:  make(map[dbus.ObjectPath]string),
+	}, nil
+}
+
+// ResolveInterval returns the interval refreshResolved re-resolves hostname
+// entries on, so callers can drive PatchAll off their own ticker at the same
+// cadence instead of guessing it.
+func (p *Patcher) ResolveInterval() time.Duration {
+	return p.interval
+}
+
+// refreshResolved re-resolves the DNS entry lists if the resolve interval
+// has elapsed since the last resolution, applying KeepRoute accumulation
+// against the previous result. Entries that fail to resolve degrade
+// per-entry (see resolveEntries) rather than aborting the whole pass.
+func (p *Patcher) refreshResolved(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.lastResolved.IsZero() && time.Since(p.lastResolved) < p.interval {
+		return
+	}
+
+	server, err := discoverServer(p.cfg.Metadata)
+	if err != nil {
+		p.logger.Warn("metadata discovery failed", "action", "metadata_discover", "error", err)
+	}
+
+	privIPv4Entries := substituteMetadata(p.cfg.PrivIPv4, server)
+	privIPv6Entries := substituteMetadata(p.cfg.PrivIPv6, server)
+	pubIPv4Entries := substituteMetadata(p.cfg.PubIPv4, server)
+	pubIPv6Entries := substituteMetadata(p.cfg.PubIPv6, server)
+
+	privIPv4 := resolveEntries(ctx, privIPv4Entries, p.resolver, p.privIPv4, p.logger)
+	privIPv6 := resolveEntries(ctx, privIPv6Entries, p.resolver, p.privIPv6, p.logger)
+	pubIPv4 := resolveEntries(ctx, pubIPv4Entries, p.resolver, p.pubIPv4, p.logger)
+	pubIPv6 := resolveEntries(ctx, pubIPv6Entries, p.resolver, p.pubIPv6, p.logger)
+
+	p.privIPv4, p.privIPv6, p.pubIPv4, p.pubIPv6 = privIPv4, privIPv6, pubIPv4, pubIPv6
+	p.privIPv4Entries, p.privIPv6Entries = privIPv4Entries, privIPv6Entries
+	p.pubIPv4Entries, p.pubIPv6Entries = pubIPv4Entries, pubIPv6Entries
+	p.lastResolved = time.Now()
+}
+
+// pendingPatch is a computed-but-not-yet-applied settings change for one
+// connection.
+type pendingPatch struct {
+	conn    gonetworkmanager.Connection
+	name    string
+	sMap    map[string]map[string]any
+	sig     string
+	private bool
+	reason  string
+}
+
+// PatchAll walks every saved connection, computes the DNS/token settings
+// appropriate to it, and -- for any connection whose settings actually
+// changed -- applies them inside an NM checkpoint so a bad push can be
+// rolled back automatically. Hostname entries are re-resolved first if the
+// resolve interval has elapsed.
+//
+// PatchAll is not safe to call concurrently with itself: two in-flight calls
+// can race to create competing NM Checkpoints. Callers driving it off
+// multiple timers/signals must serialize calls (e.g. with a mutex).
+func (p *Patcher) PatchAll() error {
+	p.refreshResolved(context.Background())
+
+	settingsSvc, err := gonetworkmanager.NewSettings()
+	if err != nil {
+		return fmt.Errorf("cannot connect to NM Settings: %w", err)
+	}
+
+	conns, err := settingsSvc.ListConnections()
+	if err != nil {
+		return fmt.Errorf("failed to list NM connections: %w", err)
+	}
+
+	var pending []*pendingPatch
+	for _, conn := range conns {
+		pp, err := p.computePatch(conn)
+		if err != nil {
+			p.logger.Error("computing patch failed", "conn_path", conn.GetPath(), "action", "skip", "error", err)
+			continue
+		}
+		if pp != nil {
+			pending = append(pending, pp)
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+	return p.applyWithCheckpoint(pending)
+}
+
+// applyWithCheckpoint wraps the actual conn.Update() calls in an NM
+// Checkpoint scoped to the devices running the affected connections, then
+// runs the configured liveness probe: on success the checkpoint is
+// destroyed (changes kept), on failure it's rolled back and every
+// connection in this batch is logged as reverted.
+func (p *Patcher) applyWithCheckpoint(pending []*pendingPatch) error {
+	bus, err := dbus.SystemBus()
+	if err != nil {
+		return fmt.Errorf("cannot connect to system bus: %w", err)
+	}
+
+	paths := make(map[dbus.ObjectPath]bool, len(pending))
+	for _, pp := range pending {
+		paths[pp.conn.GetPath()] = true
+	}
+	devices, err := activeDevicesForConnections(bus, paths)
+	if err != nil {
+		p.logger.Warn("could not scope checkpoint to affected devices, checkpointing all", "error", err)
+		devices = nil
+	}
+
+	cp, err := checkpointCreate(bus, devices, p.cfg.Checkpoint.rollbackTimeout())
+	if err != nil {
+		p.logger.Warn("checkpoint create failed, applying without rollback safety net", "error", err)
+		cp = ""
+	}
+
+	applied := make([]*pendingPatch, 0, len(pending))
+	for _, pp := range pending {
+		action := "public"
+		if pp.private {
+			action = "private"
+		}
+		if reason := pp.reason; reason != "" {
+			p.logger.Info("posture check failed, falling back to public profile",
+				"ssid", pp.name, "conn_path", pp.conn.GetPath(), "action", action, "decision_reason", reason)
+		}
+		if err := pp.conn.Update(pp.sMap); err != nil {
+			p.logger.Error("updating connection failed",
+				"ssid", pp.name, "conn_path", pp.conn.GetPath(), "action", action, "error", err)
+			continue
+		}
+		p.logger.Info("updated connection",
+			"ssid", pp.name, "conn_path", pp.conn.GetPath(), "action", action)
+		applied = append(applied, pp)
+	}
+
+	if cp == "" {
+		// No rollback safety net was available, but the updates above still
+		// went through -- commit their signatures now so the next PatchAll
+		// doesn't reapply the same settings forever just because checkpoints
+		// aren't available (old NM, insufficient permissions).
+		p.commitSignatures(applied)
+		return nil
+	}
+
+	ok, reason := p.cfg.Checkpoint.probe()
+	if ok {
+		if err := checkpointDestroy(bus, cp); err != nil {
+			p.logger.Warn("checkpoint destroy failed", "error", err)
+		}
+		p.commitSignatures(applied)
+		return nil
+	}
+
+	names := make([]string, 0, len(pending))
+	for _, pp := range pending {
+		names = append(names, pp.name)
+	}
+	p.logger.Error("liveness probe failed, rolling back",
+		"action", "rollback", "decision_reason", reason, "connections", names)
+	if _, err := checkpointRollback(bus, cp); err != nil {
+		p.logger.Warn("checkpoint rollback failed", "error", err)
+	}
+	return fmt.Errorf("rolled back %d connection(s): %s", len(pending), reason)
+}
+
+func (p *Patcher) commitSignatures(pending []*pendingPatch) {
+	p.sigMu.Lock()
+	defer p.sigMu.Unlock()
+	for _, pp := range pending {
+		p.lastSig[pp.conn.GetPath()] = pp.sig
+	}
+}
+
+// computePatch figures out the DNS/token settings for conn without applying
+// them. It returns nil if conn isn't a type we touch, or if the computed
+// settings are identical to what we last successfully pushed.
+func (p *Patcher) computePatch(conn gonetworkmanager.Connection) (*pendingPatch, error) {
+	cfg := p.cfg
+
+	p.mu.Lock()
+	privIPv6Bytes, err := netIPsToBytes(flatten(p.privIPv6Entries, p.privIPv6), true)
+	var pubIPv6Bytes [][]byte
+	var privIPv4Nums, pubIPv4Nums []uint32
+	if err == nil {
+		pubIPv6Bytes, err = netIPsToBytes(flatten(p.pubIPv6Entries, p.pubIPv6), true)
+	}
+	if err == nil {
+		privIPv4Nums, err = netIPsToUint32(flatten(p.privIPv4Entries, p.privIPv4))
+	}
+	if err == nil {
+		pubIPv4Nums, err = netIPsToUint32(flatten(p.pubIPv4Entries, p.pubIPv4))
+	}
+	p.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("invalid DNS entry in config: %w", err)
+	}
+
+	sMap, err := conn.GetSettings()
+	if err != nil {
+		return nil, fmt.Errorf("skip %s: cannot read settings: %w", conn.GetPath(), err)
+	}
+
+	// Only care about 802-11-wireless and 802-3-ethernet.
+	cType := sMap["connection"]["type"].(string)
+	if cType != "802-11-wireless" && cType != "802-3-ethernet" {
+		return nil, nil
+	}
+
+	name := sMap["connection"]["id"].(string)
+
+	ipv6 := map[string]any{
+		"method":        "auto",
+		"addr-gen-mode": int32(0), // use eui-64
+		"ip6-privacy":   int32(2),
+		"dns-priority":  int32(1),
+		"dns":           pubIPv6Bytes,
+		"token":         nil,
+	}
+
+	ipv4 := map[string]any{
+		"method":       "auto",
+		"dns-priority": int32(2),
+		"dns":          pubIPv4Nums,
+	}
+
+	private := hasPrefixAny(name, cfg.Prefixes)
+	reason := ""
+	if private && p.posture != nil {
+		if ok, why := p.posture.Evaluate(); !ok {
+			reason = why
+			private = false
+		}
+	}
+
+	if private {
+		ipv6["dns"] = privIPv6Bytes
+		ipv6["token"] = cfg.Ipv6Token
+		ipv4["dns"] = privIPv4Nums
+	} else if cType == "802-3-ethernet" {
+		delete(ipv6, "token")
+		delete(ipv6, "dns")
+		delete(ipv4, "dns")
+	}
+
+	sig := fmt.Sprintf("%v|%v|%v|%v", private, ipv6["dns"], ipv6["token"], ipv4["dns"])
+	p.sigMu.Lock()
+	unchanged := p.lastSig[conn.GetPath()] == sig
+	p.sigMu.Unlock()
+	if unchanged {
+		return nil, nil
+	}
+
+	sMap["ipv6"] = ipv6
+	sMap["ipv4"] = ipv4
+
+	return &pendingPatch{
+		conn:    conn,
+		name:    name,
+		sMap:    sMap,
+		sig:     sig,
+		private: private,
+		reason:  reason,
+	}, nil
+}
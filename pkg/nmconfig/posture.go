@@ -0,0 +1,281 @@
+package nmconfig
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PostureConfig is the YAML `posture` block. It gates whether a connection
+// that otherwise matches a private SSID prefix actually gets treated as
+// private: a stolen or unmanaged laptop that joins the corp SSID shouldn't
+// get internal resolvers or the routable IPv6 token just because the SSID
+// name matched.
+type PostureConfig struct {
+	// Mode is "and" (all rules must pass) or "or" (any rule passing is
+	// enough). Defaults to "and".
+	Mode  string              `yaml:"mode"`
+	Rules []PostureRuleConfig `yaml:"rules"`
+}
+
+// PostureRuleConfig describes a single posture rule. Only the fields
+// relevant to Type need to be set.
+type PostureRuleConfig struct {
+	Type string `yaml:"type"`
+
+	// binary_running
+	Binary string `yaml:"binary"`
+
+	// file_exists
+	Path string `yaml:"path"`
+
+	// krb_realm
+	Realm string `yaml:"realm"`
+
+	// tpm_ek_cert: compares the SHA-256 fingerprint of the PEM cert at
+	// CertPath against Fingerprint (hex, colon or bare).
+	CertPath    string `yaml:"ek_cert_path"`
+	Fingerprint string `yaml:"fingerprint"`
+}
+
+// PostureCheck is a single yes/no host-state test.
+type PostureCheck interface {
+	// Name identifies the check for logging.
+	Name() string
+	// Check reports whether the host satisfies the rule. An error means
+	// the check couldn't be evaluated at all (e.g. /proc unreadable), and
+	// is treated as a failure by PostureEvaluator.
+	Check() (bool, error)
+}
+
+// PostureEvaluator ORs/ANDs a list of PostureCheck together.
+type PostureEvaluator struct {
+	mode   string
+	checks []PostureCheck
+}
+
+// BuildPostureEvaluator turns a PostureConfig into a PostureEvaluator. A nil
+// or empty cfg yields a nil evaluator, which callers should treat as "no
+// posture requirement".
+func BuildPostureEvaluator(cfg *PostureConfig) (*PostureEvaluator, error) {
+	if cfg == nil || len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+	mode := strings.ToLower(cfg.Mode)
+	if mode == "" {
+		mode = "and"
+	}
+	if mode != "and" && mode != "or" {
+		return nil, fmt.Errorf("posture: unknown mode %q", cfg.Mode)
+	}
+
+	checks := make([]PostureCheck, 0, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		c, err := buildPostureCheck(r)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	return &PostureEvaluator{mode: mode, checks: checks}, nil
+}
+
+func buildPostureCheck(r PostureRuleConfig) (PostureCheck, error) {
+	switch r.Type {
+	case "binary_running":
+		if r.Binary == "" {
+			return nil, fmt.Errorf("posture: binary_running rule missing binary")
+		}
+		return &binaryRunningCheck{binary: r.Binary}, nil
+	case "file_exists":
+		if r.Path == "" {
+			return nil, fmt.Errorf("posture: file_exists rule missing path")
+		}
+		return &fileExistsCheck{path: r.Path}, nil
+	case "krb_realm":
+		if r.Realm == "" {
+			return nil, fmt.Errorf("posture: krb_realm rule missing realm")
+		}
+		return &krbRealmCheck{realm: r.Realm}, nil
+	case "tpm_ek_cert":
+		if r.CertPath == "" || r.Fingerprint == "" {
+			return nil, fmt.Errorf("posture: tpm_ek_cert rule missing ek_cert_path/fingerprint")
+		}
+		return &tpmEKCertCheck{certPath: r.CertPath, want: normalizeFingerprint(r.Fingerprint)}, nil
+	default:
+		return nil, fmt.Errorf("posture: unknown rule type %q", r.Type)
+	}
+}
+
+// Evaluate runs every check and returns whether the host passes, plus a
+// human-readable reason suitable for logging the decision.
+func (e *PostureEvaluator) Evaluate() (bool, string) {
+	var results []string
+	passCount := 0
+	for _, c := range e.checks {
+		ok, err := c.Check()
+		switch {
+		case err != nil:
+			results = append(results, fmt.Sprintf("%s: error (%v)", c.Name(), err))
+		case ok:
+			passCount++
+			results = append(results, fmt.Sprintf("%s: pass", c.Name()))
+		default:
+			results = append(results, fmt.Sprintf("%s: fail", c.Name()))
+		}
+	}
+
+	var ok bool
+	if e.mode == "or" {
+		ok = passCount > 0
+	} else {
+		ok = passCount == len(e.checks)
+	}
+	return ok, strings.Join(results, "; ")
+}
+
+// binaryRunningCheck passes if a process whose binary basename (or /proc
+// status Name) matches binary is currently running.
+type binaryRunningCheck struct {
+	binary string
+}
+
+func (c *binaryRunningCheck) Name() string { return "binary_running(" + c.binary + ")" }
+
+func (c *binaryRunningCheck) Check() (bool, error) {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return false, err
+	}
+	for _, p := range procs {
+		if !p.IsDir() {
+			continue
+		}
+		if _, err := strconv.Atoi(p.Name()); err != nil {
+			continue // not a pid directory
+		}
+
+		if exe, err := os.Readlink(filepath.Join("/proc", p.Name(), "exe")); err == nil {
+			if filepath.Base(exe) == c.binary {
+				return true, nil
+			}
+		}
+
+		if name, err := readProcStatusName(filepath.Join("/proc", p.Name(), "status")); err == nil {
+			if name == c.binary {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func readProcStatusName(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "Name:"); ok {
+			return strings.TrimSpace(name), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// fileExistsCheck passes if path exists.
+type fileExistsCheck struct {
+	path string
+}
+
+func (c *fileExistsCheck) Name() string { return "file_exists(" + c.path + ")" }
+
+func (c *fileExistsCheck) Check() (bool, error) {
+	_, err := os.Stat(c.path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// krbRealmCheck passes if the host currently holds a live Kerberos ticket
+// for realm, i.e. it's actually domain-joined right now rather than merely
+// imaged from a template that has realm configured somewhere. We shell out
+// to klist instead of reading /etc/krb5.conf's default_realm, since that
+// file ships with the image and says nothing about whether this particular
+// machine ever obtained a ticket.
+type krbRealmCheck struct {
+	realm string
+}
+
+func (c *krbRealmCheck) Name() string { return "krb_realm(" + c.realm + ")" }
+
+func (c *krbRealmCheck) Check() (bool, error) {
+	out, err := exec.Command("klist").Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// klist exits non-zero when the ccache is empty or every ticket
+			// in it has expired -- a normal "no valid ticket" result, not an
+			// evaluation failure.
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		principal, ok := strings.CutPrefix(strings.TrimSpace(line), "Default principal:")
+		if !ok {
+			continue
+		}
+		_, realm, ok := strings.Cut(strings.TrimSpace(principal), "@")
+		return ok && realm == c.realm, nil
+	}
+	return false, nil
+}
+
+// tpmEKCertCheck passes if the SHA-256 fingerprint of the PEM/DER cert at
+// certPath matches want. The cert itself is expected to already have been
+// extracted from the TPM (e.g. via tpm2_getekcertificate) to certPath by
+// whatever provisions the machine; we just fingerprint what's on disk.
+type tpmEKCertCheck struct {
+	certPath string
+	want     string
+}
+
+func (c *tpmEKCertCheck) Name() string { return "tpm_ek_cert(" + c.certPath + ")" }
+
+func (c *tpmEKCertCheck) Check() (bool, error) {
+	data, err := os.ReadFile(c.certPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	return got == c.want, nil
+}
+
+func normalizeFingerprint(fp string) string {
+	fp = strings.ToLower(fp)
+	fp = strings.ReplaceAll(fp, ":", "")
+	return strings.TrimSpace(fp)
+}
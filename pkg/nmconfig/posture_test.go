@@ -0,0 +1,92 @@
+package nmconfig
+
+import "testing"
+
+func TestNormalizeFingerprint(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"AA:BB:CC", "aabbcc"},
+		{" aa:bb:cc ", "aabbcc"},
+		{"ABCDEF01", "abcdef01"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if got := normalizeFingerprint(c.in); got != c.want {
+			t.Errorf("normalizeFingerprint(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+// stubCheck is a PostureCheck with a canned result, for exercising
+// PostureEvaluator's and/or logic without touching the filesystem or
+// shelling out.
+type stubCheck struct {
+	name string
+	ok   bool
+	err  error
+}
+
+func (c *stubCheck) Name() string         { return c.name }
+func (c *stubCheck) Check() (bool, error) { return c.ok, c.err }
+
+func TestPostureEvaluatorAndMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []PostureCheck
+		want   bool
+	}{
+		{"all pass", []PostureCheck{&stubCheck{name: "a", ok: true}, &stubCheck{name: "b", ok: true}}, true},
+		{"one fails", []PostureCheck{&stubCheck{name: "a", ok: true}, &stubCheck{name: "b", ok: false}}, false},
+		{"one errors", []PostureCheck{&stubCheck{name: "a", ok: true}, &stubCheck{name: "b", err: errTest}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &PostureEvaluator{mode: "and", checks: tt.checks}
+			if got, reason := e.Evaluate(); got != tt.want {
+				t.Errorf("Evaluate() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostureEvaluatorOrMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []PostureCheck
+		want   bool
+	}{
+		{"one passes", []PostureCheck{&stubCheck{name: "a", ok: false}, &stubCheck{name: "b", ok: true}}, true},
+		{"none pass", []PostureCheck{&stubCheck{name: "a", ok: false}, &stubCheck{name: "b", ok: false}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &PostureEvaluator{mode: "or", checks: tt.checks}
+			if got, reason := e.Evaluate(); got != tt.want {
+				t.Errorf("Evaluate() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPostureEvaluatorNilOrEmpty(t *testing.T) {
+	if e, err := BuildPostureEvaluator(nil); e != nil || err != nil {
+		t.Fatalf("BuildPostureEvaluator(nil) = %v, %v, want nil, nil", e, err)
+	}
+	if e, err := BuildPostureEvaluator(&PostureConfig{}); e != nil || err != nil {
+		t.Fatalf("BuildPostureEvaluator(empty) = %v, %v, want nil, nil", e, err)
+	}
+}
+
+func TestBuildPostureEvaluatorUnknownRuleType(t *testing.T) {
+	_, err := BuildPostureEvaluator(&PostureConfig{Rules: []PostureRuleConfig{{Type: "nonexistent"}}})
+	if err == nil {
+		t.Fatal("expected an error for an unknown rule type")
+	}
+}
+
+var errTest = &stubError{"stub check failure"}
+
+type stubError struct{ s string }
+
+func (e *stubError) Error() string { return e.s }
@@ -0,0 +1,121 @@
+package nmconfig
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+)
+
+// resolvedList is the result of resolving a []DNSEntry: the per-entry answer
+// sets, keyed by entry value. flatten turns it into the ordered address list
+// handed to NM, and the next refresh reads it to apply KeepRoute
+// accumulation.
+type resolvedList struct {
+	flat map[string][]net.IP // entry value -> its current addresses
+}
+
+// resolveEntries expands entries into concrete addresses via resolver.
+// prev is the previous resolvedList for the same entry list (nil on first
+// resolution); entries with KeepRoute set have their previously-resolved
+// addresses unioned into the new result rather than replaced.
+//
+// A single entry failing to resolve (e.g. no working resolver yet) doesn't
+// abort the whole list: that entry falls back to whatever prev last
+// resolved for it, or is dropped from this pass if there's no prior result,
+// so one bad hostname can't block every other entry's patch.
+func resolveEntries(ctx context.Context, entries []DNSEntry, resolver Resolver, prev *resolvedList, logger *slog.Logger) *resolvedList {
+	out := &resolvedList{flat: make(map[string][]net.IP, len(entries))}
+	for _, e := range entries {
+		ips, err := resolveOne(ctx, e.Value, resolver)
+		if err != nil {
+			if prev != nil {
+				if cached, ok := prev.flat[e.Value]; ok {
+					logger.Warn("re-resolve failed, keeping last-good addresses",
+						"entry", e.Value, "action", "resolve", "error", err)
+					out.flat[e.Value] = cached
+					continue
+				}
+			}
+			logger.Warn("resolve failed, entry has no addresses this pass",
+				"entry", e.Value, "action", "resolve", "error", err)
+			continue
+		}
+		if e.KeepRoute && prev != nil {
+			ips = unionIPs(prev.flat[e.Value], ips)
+		}
+		out.flat[e.Value] = ips
+	}
+	return out
+}
+
+func resolveOne(ctx context.Context, value string, resolver Resolver) ([]net.IP, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	if ip, _, err := net.ParseCIDR(value); err == nil {
+		return []net.IP{ip}, nil
+	}
+	return resolver.LookupIPs(ctx, value)
+}
+
+func unionIPs(a, b []net.IP) []net.IP {
+	seen := make(map[string]bool, len(a)+len(b))
+	out := make([]net.IP, 0, len(a)+len(b))
+	for _, ip := range a {
+		s := ip.String()
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, ip)
+	}
+	for _, ip := range b {
+		s := ip.String()
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, ip)
+	}
+	return out
+}
+
+// flatten returns the addresses for entries, in entry order, from a
+// resolvedList produced by resolveEntries.
+func flatten(entries []DNSEntry, rl *resolvedList) []net.IP {
+	var out []net.IP
+	for _, e := range entries {
+		out = append(out, rl.flat[e.Value]...)
+	}
+	return out
+}
+
+func netIPsToBytes(ips []net.IP, v6 bool) ([][]byte, error) {
+	var out [][]byte
+	for _, ip := range ips {
+		var b net.IP
+		if v6 {
+			b = ip.To16()
+		} else {
+			b = ip.To4()
+		}
+		if b == nil {
+			return nil, fmt.Errorf("address %s is not valid for this family", ip)
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func netIPsToUint32(ips []net.IP) ([]uint32, error) {
+	bs, err := netIPsToBytes(ips, false)
+	if err != nil {
+		return nil, err
+	}
+	var out []uint32
+	for _, b := range bs {
+		out = append(out, uint32(b[0])<<24|uint32(b[1])<<16|uint32(b[2])<<8|uint32(b[3]))
+	}
+	return out, nil
+}
@@ -0,0 +1,50 @@
+package nmconfig
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Resolver looks up the IP addresses for a hostname. It's an interface so a
+// bootstrap resolver pointed at a specific upstream can be substituted when
+// the machine doesn't yet have a working system resolver -- which is
+// exactly the chicken-and-egg situation this package exists to get out of.
+type Resolver interface {
+	LookupIPs(ctx context.Context, host string) ([]net.IP, error)
+}
+
+// systemResolver defers to the OS's normal resolution (/etc/resolv.conf).
+type systemResolver struct{}
+
+// NewSystemResolver returns a Resolver backed by the OS's normal resolution.
+func NewSystemResolver() Resolver { return systemResolver{} }
+
+func (systemResolver) LookupIPs(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// BootstrapResolver resolves hostnames by querying a fixed upstream DNS
+// server directly, bypassing /etc/resolv.conf entirely. Point it at a
+// well-known public resolver (or an internal one reachable without DNS) so
+// priv_ipv4/priv_ipv6 hostnames can still resolve on a connection whose only
+// configured resolvers are the ones we're in the middle of pushing.
+type BootstrapResolver struct {
+	Upstream string // host:port, e.g. "1.1.1.1:53"
+	Timeout  time.Duration
+}
+
+func (b *BootstrapResolver) LookupIPs(ctx context.Context, host string) ([]net.IP, error) {
+	timeout := b.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, b.Upstream)
+		},
+	}
+	return r.LookupIP(ctx, "ip", host)
+}
@@ -0,0 +1,39 @@
+package wifiprio
+
+// bandForFrequency buckets an AP's real Frequency property (MHz, as already
+// fetched by getWifiNetworks) into a band name. This replaces the old
+// getFrequencyPriority, which guessed the band from SSID text like "5g" or
+// "6ghz" -- unreliable since plenty of APs don't encode band in the SSID at
+// all, or lie about it.
+func bandForFrequency(freqMHz uint32) string {
+	switch {
+	case freqMHz >= 5925 && freqMHz <= 7125:
+		return "6ghz"
+	case freqMHz >= 5150 && freqMHz <= 5895:
+		return "5ghz"
+	case freqMHz >= 2400 && freqMHz <= 2495:
+		return "2.4ghz"
+	default:
+		return ""
+	}
+}
+
+// signalMinPenalty is subtracted from a network's score when its strength is
+// below cfg.SignalMin, so a weak-but-known network still loses to a
+// good-enough one even after the per-band bonus. It doesn't apply to the
+// currently active SSID, so we don't deprioritize the connection we're
+// already on just because its signal dipped.
+const signalMinPenalty = 100
+
+// score combines signal strength with the configured per-band weight. A
+// WirelessCapabilities-derived channel-utilization term would belong here
+// too, but gonetworkmanager doesn't expose HE/EHT flags on AccessPoint, so
+// the only related steering is the dwell scanner (scanner.go), which backs
+// off scanning for known SSIDs that keep coming back empty.
+func score(cfg *Config, n *Network, current string) int {
+	s := int(n.Strength) + cfg.BandWeights[bandForFrequency(n.Frequency)]
+	if int(n.Strength) < cfg.SignalMin && n.SSID != current {
+		s -= signalMinPenalty
+	}
+	return s
+}
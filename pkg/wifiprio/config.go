@@ -0,0 +1,44 @@
+package wifiprio
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config tunes how Manager scores and prioritizes visible networks.
+type Config struct {
+	// SignalMin is the minimum strength (0-100) a network needs before it's
+	// treated as "good enough" rather than just "better than nothing".
+	SignalMin int `yaml:"signal_min"`
+
+	// BandWeights adds a per-band bonus to a network's score, keyed by
+	// "2.4ghz", "5ghz", "6ghz". Missing keys default to 0.
+	BandWeights map[string]int `yaml:"band_weights"`
+}
+
+// DefaultConfig mirrors the weights the daemon used before this was
+// configurable.
+func DefaultConfig() *Config {
+	return &Config{
+		SignalMin: SignalMin,
+		BandWeights: map[string]int{
+			"2.4ghz": 24,
+			"5ghz":   50,
+			"6ghz":   60,
+		},
+	}
+}
+
+// LoadConfig reads and parses the YAML config at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := DefaultConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
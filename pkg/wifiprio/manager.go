@@ -0,0 +1,127 @@
+package wifiprio
+
+import (
+	"log/slog"
+	"sort"
+
+	gonm "github.com/Wifx/gonetworkmanager"
+)
+
+// SignalMin is the minimum strength (0-100) a network needs before it's
+// treated as "good enough" rather than just "better than nothing".
+const SignalMin = 15
+
+// Manager holds whatever state needs to persist between Reconcile calls.
+type Manager struct {
+	cfg    *Config
+	logger *slog.Logger
+}
+
+// NewManager returns a ready-to-use Manager scoring networks per cfg. A nil
+// cfg falls back to DefaultConfig(); a nil logger falls back to
+// slog.Default().
+func NewManager(cfg *Config, logger *slog.Logger) *Manager {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Manager{cfg: cfg, logger: logger}
+}
+
+// Reconcile scans for visible networks, reorders autoconnect-priority so the
+// best one wins, and activates it if it isn't already the active connection.
+//
+// This is what used to be the body of the wifi_manager.go polling loop; it's
+// now called from the daemon's D-Bus event loop instead of on a 30s timer.
+//
+// Reconcile is not safe to call concurrently with itself; callers driving it
+// off multiple timers/signals must serialize calls (e.g. with a mutex).
+func (m *Manager) Reconcile() {
+	m.logger.Debug("starting scan logic", "action", "scan")
+
+	known, _ := getKnownNetworks()
+	avail, _ := getWifiNetworks()
+	current, _ := getCurrentConnection()
+
+	// mark known
+	for _, net := range avail {
+		for _, ks := range known {
+			if net.SSID == ks {
+				net.IsKnown = true
+			}
+		}
+	}
+
+	// sort: known first, then by combined signal+band score
+	sort.Slice(avail, func(i, j int) bool {
+		a, b := avail[i], avail[j]
+		if a.IsKnown != b.IsKnown {
+			return a.IsKnown
+		}
+		return score(m.cfg, a, current) > score(m.cfg, b, current)
+	})
+
+	for _, net := range avail {
+		m.logger.Debug("visible network",
+			"ssid", net.SSID, "band", bandForFrequency(net.Frequency),
+			"strength", net.Strength, "action", "rank")
+	}
+
+	// set priorities
+	for idx, net := range avail {
+		paths, _ := getConnectionsBySSID(net.SSID)
+		prio := int32(len(avail) - idx + 10)
+		setWiFiPriority(m.logger, paths, prio)
+	}
+
+	if len(avail) == 0 {
+		m.logger.Info("no networks found", "action", "no_networks")
+		return
+	}
+
+	best := avail[0].SSID
+	if best == current {
+		return
+	}
+	paths, _ := getConnectionsBySSID(best)
+	if len(paths) == 0 {
+		return
+	}
+
+	nm, err := gonm.NewNetworkManager()
+	if err != nil {
+		return
+	}
+	devs, err := nm.GetDevices()
+	if err != nil {
+		return
+	}
+	for _, d := range devs {
+		t, _ := d.GetPropertyDeviceType()
+		if t != gonm.NmDeviceTypeWifi {
+			continue
+		}
+		dw, err := gonm.NewDeviceWireless(d.GetPath())
+		if err != nil {
+			continue
+		}
+		aps, err := dw.GetAccessPoints()
+		if err != nil {
+			continue
+		}
+		var target *gonm.AccessPoint
+		for _, ap := range aps {
+			if ss, _ := ap.GetPropertySSID(); ss == best {
+				target = &ap
+				break
+			}
+		}
+		if target != nil {
+			conn, _ := gonm.NewConnection(paths[0])
+			nm.ActivateWirelessConnection(conn, d, *target)
+			m.logger.Info("connecting", "ssid", best, "action", "connect")
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package wifiprio
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func setWiFiPriority(logger *slog.Logger, conns []dbus.ObjectPath, priority int32) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		logger.Error("failed to connect to system bus", "action", "set_priority", "error", err)
+		return
+	}
+
+	for _, objPath := range conns {
+		obj := conn.Object("org.freedesktop.NetworkManager", objPath)
+
+		// 1) Retrieve current settings
+		var settings map[string]map[string]dbus.Variant
+		getCall := obj.Call(
+			"org.freedesktop.NetworkManager.Settings.Connection.GetSettings",
+			0,
+		)
+		if getCall.Err != nil {
+			logger.Warn("GetSettings failed", "conn_path", objPath, "action", "set_priority", "error", getCall.Err)
+			continue
+		}
+		if err := getCall.Store(&settings); err != nil {
+			logger.Warn("could not parse settings", "conn_path", objPath, "action", "set_priority", "error", err)
+			continue
+		}
+
+		// 2) Update autoconnect-priority
+		connGrp, ok := settings["connection"]
+		if !ok {
+			connGrp = make(map[string]dbus.Variant)
+		}
+		connGrp["autoconnect-priority"] = dbus.MakeVariant(priority)
+		settings["connection"] = connGrp
+
+		// 3) Push updated settings back
+		updateCall := obj.Call(
+			"org.freedesktop.NetworkManager.Settings.Connection.Update",
+			0,
+			settings,
+		)
+		if updateCall.Err != nil {
+			logger.Warn("Update failed", "conn_path", objPath, "action", "set_priority", "error", updateCall.Err)
+			continue
+		}
+
+		logger.Debug("priority set", "conn_path", objPath, "action", "set_priority", "decision_reason", fmt.Sprintf("priority=%d", priority))
+	}
+}
+
+// getConnectionsBySSID finds saved connection paths for an SSID.
+func getConnectionsBySSID(ssid string) ([]dbus.ObjectPath, error) {
+	settingsObj := dbus.ObjectPath("/org/freedesktop/NetworkManager/Settings")
+	bus, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	obj := bus.Object("org.freedesktop.NetworkManager", settingsObj)
+	var paths []dbus.ObjectPath
+	if err := obj.Call("org.freedesktop.NetworkManager.Settings.ListConnections", 0).Store(&paths); err != nil {
+		return nil, err
+	}
+	var matches []dbus.ObjectPath
+	for _, p := range paths {
+		cobj := bus.Object("org.freedesktop.NetworkManager", p)
+		var cs map[string]map[string]dbus.Variant
+		if err := cobj.Call("org.freedesktop.NetworkManager.Settings.Connection.GetSettings", 0).Store(&cs); err != nil {
+			continue
+		}
+		if cs["connection"]["type"].Value() == "802-11-wireless" {
+			b := cs["802-11-wireless"]["ssid"].Value().([]uint8)
+			if string(b) == ssid {
+				matches = append(matches, p)
+			}
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,240 @@
+// Package wifiprio picks which known Wi-Fi network to prefer and pushes
+// NetworkManager autoconnect-priority accordingly.
+//
+// This used to be the body of the laptop_wifi_priority_service main loop,
+// polling every 30s. It's now a library the daemon drives off D-Bus signals.
+package wifiprio
+
+import (
+	"fmt"
+	"time"
+
+	gonm "github.com/Wifx/gonetworkmanager"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	// scanPollInterval is how often we poll LastScan while waiting for a
+	// requested scan to finish.
+	scanPollInterval = 250 * time.Millisecond
+
+	// scanWaitTimeout bounds how long we'll wait for LastScan to advance
+	// before giving up and reading whatever results are on hand, so a device
+	// that never reports a finished scan can't wedge the dwell rotation.
+	scanWaitTimeout = 10 * time.Second
+)
+
+// Network is one SSID seen either from a scan or from saved connections.
+type Network struct {
+	SSID      string
+	Frequency uint32
+	Strength  uint8
+	IsKnown   bool
+}
+
+// getKnownNetworks returns SSIDs of saved Wi-Fi connections that have been
+// used at least once (i.e. have a connection.timestamp).
+func getKnownNetworks() ([]string, error) {
+	settings, err := gonm.NewSettings()
+	if err != nil {
+		return nil, err
+	}
+	conns, err := settings.ListConnections()
+	if err != nil {
+		return nil, err
+	}
+	var known []string
+	for _, conn := range conns {
+		cs, err := conn.GetSettings()
+		if err != nil {
+			continue
+		}
+		if cs["connection"]["type"] == "802-11-wireless" {
+			ssidBytes := cs["802-11-wireless"]["ssid"].([]uint8)
+			ssid := string(ssidBytes)
+			if _, ok := cs["connection"]["timestamp"]; ok {
+				known = append(known, ssid)
+			}
+		}
+	}
+	return known, nil
+}
+
+// getCurrentConnection returns the SSID of the active Wi-Fi network, or ""
+// if none is active.
+func getCurrentConnection() (string, error) {
+	nm, err := gonm.NewNetworkManager()
+	if err != nil {
+		return "", err
+	}
+	devs, err := nm.GetDevices()
+	if err != nil {
+		return "", err
+	}
+	for _, d := range devs {
+		t, err := d.GetPropertyDeviceType()
+		if err != nil || t != gonm.NmDeviceTypeWifi {
+			continue
+		}
+		state, err := d.GetPropertyState()
+		if err != nil || state != gonm.NmDeviceStateActivated {
+			continue
+		}
+		dw, err := gonm.NewDeviceWireless(d.GetPath())
+		if err != nil {
+			continue
+		}
+		ap, err := dw.GetPropertyActiveAccessPoint()
+		if err != nil || ap == nil {
+			continue
+		}
+		ssid, err := ap.GetPropertySSID()
+		if err != nil {
+			return "", err
+		}
+		return ssid, nil
+	}
+	return "", nil
+}
+
+// KnownSSIDs exposes getKnownNetworks for callers outside the package, e.g.
+// the daemon seeding a DwellScanner's round-robin list.
+func KnownSSIDs() ([]string, error) {
+	return getKnownNetworks()
+}
+
+// RequestScanForSSID asks NM to rescan for a single SSID, waits for the scan
+// to actually finish, and reports how long that took and how many APs came
+// back matching it -- the two numbers DwellScanner.Record needs to decide on
+// backoff.
+//
+// RequestScan itself is asynchronous: NM returns from the D-Bus call before
+// the scan completes and signals completion later by bumping each device's
+// LastScan property. Reading getWifiNetworks() right after the call would
+// just return whatever the previous scan cached.
+func RequestScanForSSID(ssid string) (time.Duration, int, error) {
+	nm, err := gonm.NewNetworkManager()
+	if err != nil {
+		return 0, 0, err
+	}
+	devs, err := nm.GetDevices()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	bus, err := dbus.SystemBus()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := time.Now()
+	var scanned []gonm.DeviceWireless
+	baseline := make(map[dbus.ObjectPath]int64, len(devs))
+	for _, d := range devs {
+		t, err := d.GetPropertyDeviceType()
+		if err != nil || t != gonm.NmDeviceTypeWifi {
+			continue
+		}
+		dw, err := gonm.NewDeviceWireless(d.GetPath())
+		if err != nil {
+			continue
+		}
+		last, err := dw.GetPropertyLastScan()
+		if err != nil {
+			continue
+		}
+
+		// gonetworkmanager's RequestScan() doesn't expose the options dict,
+		// so call the D-Bus method directly to filter to this SSID.
+		obj := bus.Object("org.freedesktop.NetworkManager", d.GetPath())
+		call := obj.Call("org.freedesktop.NetworkManager.Device.Wireless.RequestScan", 0,
+			map[string]dbus.Variant{"ssids": dbus.MakeVariant([][]byte{[]byte(ssid)})})
+		if call.Err != nil {
+			continue
+		}
+		baseline[d.GetPath()] = last
+		scanned = append(scanned, dw)
+	}
+	if len(scanned) == 0 {
+		return time.Since(start), 0, fmt.Errorf("no wifi device accepted RequestScan for %q", ssid)
+	}
+
+	waitForScan(scanned, baseline)
+	elapsed := time.Since(start)
+
+	avail, err := getWifiNetworks()
+	if err != nil {
+		return elapsed, 0, err
+	}
+	found := 0
+	for _, net := range avail {
+		if net.SSID == ssid {
+			found++
+		}
+	}
+	return elapsed, found, nil
+}
+
+// waitForScan polls each device's LastScan property until it advances past
+// baseline (i.e. the scan we just requested has finished) or scanWaitTimeout
+// elapses, whichever comes first.
+func waitForScan(devices []gonm.DeviceWireless, baseline map[dbus.ObjectPath]int64) {
+	deadline := time.Now().Add(scanWaitTimeout)
+	for time.Now().Before(deadline) {
+		done := true
+		for _, dw := range devices {
+			last, err := dw.GetPropertyLastScan()
+			if err != nil {
+				continue
+			}
+			if last == baseline[dw.GetPath()] {
+				done = false
+				break
+			}
+		}
+		if done {
+			return
+		}
+		time.Sleep(scanPollInterval)
+	}
+}
+
+// getWifiNetworks returns the access points currently visible to any Wi-Fi
+// device, as of NM's last completed scan. It does not request a new scan.
+func getWifiNetworks() ([]*Network, error) {
+	nm, err := gonm.NewNetworkManager()
+	if err != nil {
+		return nil, err
+	}
+	devs, err := nm.GetDevices()
+	if err != nil {
+		return nil, err
+	}
+	var list []*Network
+	for _, d := range devs {
+		t, err := d.GetPropertyDeviceType()
+		if err != nil || t != gonm.NmDeviceTypeWifi {
+			continue
+		}
+		dw, err := gonm.NewDeviceWireless(d.GetPath())
+		if err != nil {
+			continue
+		}
+		aps, err := dw.GetAccessPoints()
+		if err != nil {
+			continue
+		}
+		for _, ap := range aps {
+			ssid, _ := ap.GetPropertySSID()
+			freq, _ := ap.GetPropertyFrequency()
+			strength, _ := ap.GetPropertyStrength()
+			list = append(list, &Network{
+				SSID:      ssid,
+				Frequency: freq,
+				Strength:  strength,
+				IsKnown:   false,
+			})
+		}
+	}
+	return list, nil
+}
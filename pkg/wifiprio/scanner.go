@@ -0,0 +1,118 @@
+package wifiprio
+
+import (
+	"sync"
+	"time"
+)
+
+// DwellScanner round-robins targeted rescans across known SSIDs instead of
+// one blanket RequestScan across every device every tick. NM's RequestScan
+// only lets us target specific SSIDs (no literal RF channel selection), so
+// "channel dwell" here means: spend the next scan on one known network at a
+// time, and stop wasting cycles on ones that reliably come back empty.
+//
+// DwellScanner itself only tracks state; callers do the actual D-Bus call
+// and report the outcome back via Record.
+type DwellScanner struct {
+	mu      sync.Mutex
+	order   []string
+	targets map[string]*dwellTarget
+	next    int
+
+	// baseBackoff is the backoff applied after the first empty result;
+	// it doubles (capped at maxBackoff) per additional consecutive miss.
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+type dwellTarget struct {
+	consecutiveEmpty int
+	backoffUntil     time.Time
+}
+
+// NewDwellScanner returns a DwellScanner with sensible defaults.
+func NewDwellScanner() *DwellScanner {
+	return &DwellScanner{
+		targets:     make(map[string]*dwellTarget),
+		baseBackoff: 30 * time.Second,
+		maxBackoff:  30 * time.Minute,
+	}
+}
+
+// SetTargets updates the round-robin SSID list, e.g. from getKnownNetworks.
+// SSIDs that already have backoff state keep it; new ones start fresh.
+func (s *DwellScanner) SetTargets(ssids []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool, len(ssids))
+	s.order = s.order[:0]
+	for _, ssid := range ssids {
+		seen[ssid] = true
+		s.order = append(s.order, ssid)
+		if _, ok := s.targets[ssid]; !ok {
+			s.targets[ssid] = &dwellTarget{}
+		}
+	}
+	for ssid := range s.targets {
+		if !seen[ssid] {
+			delete(s.targets, ssid)
+		}
+	}
+	if s.next >= len(s.order) {
+		s.next = 0
+	}
+}
+
+// Next returns the next SSID due for a targeted scan, skipping any still
+// under backoff. ok is false if every target is currently backed off.
+func (s *DwellScanner) Next() (ssid string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(s.order); i++ {
+		idx := (s.next + i) % len(s.order)
+		candidate := s.order[idx]
+		t := s.targets[candidate]
+		if t == nil || now.After(t.backoffUntil) {
+			s.next = idx + 1
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// Record reports the outcome of a targeted scan: how many APs came back for
+// that SSID and how long the scan took. Consecutive empty results push the
+// target's backoff out exponentially, up to maxBackoff.
+func (s *DwellScanner) Record(ssid string, found int, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.targets[ssid]
+	if !ok {
+		t = &dwellTarget{}
+		s.targets[ssid] = t
+	}
+
+	if found > 0 {
+		t.consecutiveEmpty = 0
+		t.backoffUntil = time.Time{}
+		return
+	}
+
+	t.consecutiveEmpty++
+	backoff := s.baseBackoff << uint(min(t.consecutiveEmpty-1, 6))
+	if backoff > s.maxBackoff || backoff <= 0 {
+		backoff = s.maxBackoff
+	}
+	t.backoffUntil = time.Now().Add(backoff)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,92 @@
+package wifiprio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDwellScannerRoundRobin(t *testing.T) {
+	s := NewDwellScanner()
+	s.SetTargets([]string{"a", "b"})
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		ssid, ok := s.Next()
+		if !ok {
+			t.Fatalf("Next() reported no target on iteration %d", i)
+		}
+		seen[ssid] = true
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Fatalf("expected both targets to come up in two calls, got %v", seen)
+	}
+}
+
+func TestDwellScannerBacksOffEmptyResults(t *testing.T) {
+	s := NewDwellScanner()
+	s.SetTargets([]string{"a", "b"})
+
+	s.Record("a", 0, time.Millisecond)
+	target := s.targets["a"]
+	if target.consecutiveEmpty != 1 {
+		t.Fatalf("consecutiveEmpty = %d, want 1", target.consecutiveEmpty)
+	}
+	if !target.backoffUntil.After(time.Now()) {
+		t.Fatal("expected backoffUntil to be in the future after an empty result")
+	}
+
+	// With "a" backed off, every remaining Next() should return "b".
+	for i := 0; i < 2; i++ {
+		ssid, ok := s.Next()
+		if !ok || ssid != "b" {
+			t.Fatalf("Next() = %q, %v, want \"b\", true", ssid, ok)
+		}
+	}
+}
+
+func TestDwellScannerBackoffDoublesAndCaps(t *testing.T) {
+	s := NewDwellScanner()
+	s.SetTargets([]string{"a"})
+
+	var last time.Duration
+	for i := 0; i < 10; i++ {
+		s.Record("a", 0, time.Millisecond)
+		got := time.Until(s.targets["a"].backoffUntil)
+		// Allow a small amount of slack for time elapsed between Record()
+		// and time.Until() -- what we care about is that backoff trends up
+		// then plateaus at maxBackoff, not sub-millisecond precision.
+		if i > 0 && got < last-time.Second {
+			t.Fatalf("backoff shrank on consecutive empty result %d: %v -> %v", i, last, got)
+		}
+		last = got
+	}
+	if last > s.maxBackoff+time.Second {
+		t.Fatalf("backoff %v exceeded maxBackoff %v", last, s.maxBackoff)
+	}
+}
+
+func TestDwellScannerFoundResetsBackoff(t *testing.T) {
+	s := NewDwellScanner()
+	s.SetTargets([]string{"a"})
+
+	s.Record("a", 0, time.Millisecond)
+	s.Record("a", 3, time.Millisecond)
+
+	target := s.targets["a"]
+	if target.consecutiveEmpty != 0 {
+		t.Fatalf("consecutiveEmpty = %d, want 0 after a found result", target.consecutiveEmpty)
+	}
+	if !target.backoffUntil.IsZero() {
+		t.Fatalf("backoffUntil = %v, want zero after a found result", target.backoffUntil)
+	}
+}
+
+func TestDwellScannerNextEmptyWhenAllBackedOff(t *testing.T) {
+	s := NewDwellScanner()
+	s.SetTargets([]string{"a"})
+	s.Record("a", 0, time.Millisecond)
+
+	if _, ok := s.Next(); ok {
+		t.Fatal("expected Next() to report no target when the only one is backed off")
+	}
+}